@@ -0,0 +1,134 @@
+package auth
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Auth assigns roles to nicknames and gates connections against a
+// BanList. Admins are identified either by an on-disk config file
+// (loaded once at startup) or, when FirstAdmin is enabled, by
+// whichever nickname claims the role first.
+type Auth struct {
+	Bans *BanList
+
+	mu           sync.RWMutex
+	adminNicks   map[string]bool
+	adminFps     map[string]bool
+	ops          map[string]bool
+	firstAdmin   bool
+	firstClaimed bool
+}
+
+// New creates an Auth backed by the ban list at bansPath. If
+// adminConfigPath is non-empty it is loaded as a list of permanent
+// admins. firstAdmin, when true, promotes the first nickname to ever
+// connect to admin.
+func New(bansPath, adminConfigPath string, firstAdmin bool) (*Auth, error) {
+	bans, err := NewBanList(bansPath)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &Auth{
+		Bans:       bans,
+		adminNicks: make(map[string]bool),
+		adminFps:   make(map[string]bool),
+		ops:        make(map[string]bool),
+		firstAdmin: firstAdmin,
+	}
+
+	if adminConfigPath != "" {
+		if err := a.loadAdminConfig(adminConfigPath); err != nil {
+			return nil, err
+		}
+	}
+
+	return a, nil
+}
+
+// loadAdminConfig reads "nick:<name>" and "fingerprint:<fp>" lines,
+// ignoring blanks and "#" comments.
+func (a *Auth) loadAdminConfig(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to open admin config: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "nick:"):
+			a.adminNicks[strings.TrimPrefix(line, "nick:")] = true
+		case strings.HasPrefix(line, "fingerprint:"):
+			a.adminFps[strings.TrimPrefix(line, "fingerprint:")] = true
+		default:
+			a.adminNicks[line] = true
+		}
+	}
+	return scanner.Err()
+}
+
+// ClaimFirstAdmin promotes nick to admin if -first-admin is enabled
+// and no one has claimed the role yet. It reports whether nick became
+// the first admin.
+func (a *Auth) ClaimFirstAdmin(nick string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if !a.firstAdmin || a.firstClaimed {
+		return false
+	}
+	a.firstClaimed = true
+	a.adminNicks[nick] = true
+	return true
+}
+
+// IsAdmin reports whether nick has admin privileges.
+func (a *Auth) IsAdmin(nick string) bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.adminNicks[nick]
+}
+
+// IsOp reports whether nick has op or admin privileges.
+func (a *Auth) IsOp(nick string) bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.adminNicks[nick] || a.ops[nick]
+}
+
+// Role returns the highest role assigned to nick.
+func (a *Auth) Role(nick string) Role {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	switch {
+	case a.adminNicks[nick]:
+		return RoleAdmin
+	case a.ops[nick]:
+		return RoleOp
+	default:
+		return RoleUser
+	}
+}
+
+// Op promotes nick to the op role. Only admins may call this; the
+// caller is responsible for that check.
+func (a *Auth) Op(nick string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.ops[nick] = true
+}
@@ -0,0 +1,238 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// ban records a single banned value and the time at which the ban
+// expires. A zero Expires means the ban never expires.
+type ban struct {
+	Value   string    `json:"value"`
+	Expires time.Time `json:"expires,omitempty"`
+}
+
+func (b ban) expired() bool {
+	return !b.Expires.IsZero() && time.Now().After(b.Expires)
+}
+
+// banListFile is the on-disk JSON shape written to the path given by
+// the -bans flag.
+type banListFile struct {
+	IPs          []ban `json:"ips"`
+	Nicknames    []ban `json:"nicknames"`
+	Fingerprints []ban `json:"fingerprints"`
+}
+
+// BanList tracks banned IPs, nicknames, and public-key fingerprints
+// (the latter unused until the server grows an SSH/TLS listener) and
+// persists them as JSON so bans survive a restart.
+type BanList struct {
+	mu           sync.RWMutex
+	path         string
+	ips          map[string]ban
+	nicknames    map[string]ban
+	fingerprints map[string]ban
+}
+
+// NewBanList loads the ban list from path, treating a missing file as
+// an empty list so the server can start fresh.
+func NewBanList(path string) (*BanList, error) {
+	bl := &BanList{
+		path:         path,
+		ips:          make(map[string]ban),
+		nicknames:    make(map[string]ban),
+		fingerprints: make(map[string]ban),
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return bl, nil
+		}
+		return nil, fmt.Errorf("failed to read ban list: %w", err)
+	}
+
+	var file banListFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse ban list: %w", err)
+	}
+
+	for _, b := range file.IPs {
+		bl.ips[b.Value] = b
+	}
+	for _, b := range file.Nicknames {
+		bl.nicknames[b.Value] = b
+	}
+	for _, b := range file.Fingerprints {
+		bl.fingerprints[b.Value] = b
+	}
+
+	return bl, nil
+}
+
+// purgeExpired drops expired entries from every set so the persisted
+// file doesn't grow without bound over a long-running server's
+// lifetime. Callers must hold bl.mu.
+func (bl *BanList) purgeExpired() {
+	for value, b := range bl.ips {
+		if b.expired() {
+			delete(bl.ips, value)
+		}
+	}
+	for value, b := range bl.nicknames {
+		if b.expired() {
+			delete(bl.nicknames, value)
+		}
+	}
+	for value, b := range bl.fingerprints {
+		if b.expired() {
+			delete(bl.fingerprints, value)
+		}
+	}
+}
+
+// save purges expired bans and writes what remains to disk. Callers
+// must hold bl.mu.
+func (bl *BanList) save() error {
+	bl.purgeExpired()
+
+	if bl.path == "" {
+		return nil
+	}
+
+	file := banListFile{}
+	for _, b := range bl.ips {
+		file.IPs = append(file.IPs, b)
+	}
+	for _, b := range bl.nicknames {
+		file.Nicknames = append(file.Nicknames, b)
+	}
+	for _, b := range bl.fingerprints {
+		file.Fingerprints = append(file.Fingerprints, b)
+	}
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode ban list: %w", err)
+	}
+
+	if err := os.WriteFile(bl.path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write ban list: %w", err)
+	}
+	return nil
+}
+
+// expiresAt turns a duration into an absolute expiry time. A zero
+// duration means "never expires".
+func expiresAt(d time.Duration) time.Time {
+	if d <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(d)
+}
+
+// BanIP bans an IP address for the given duration (0 = permanent).
+func (bl *BanList) BanIP(ip string, d time.Duration) error {
+	bl.mu.Lock()
+	defer bl.mu.Unlock()
+	bl.ips[ip] = ban{Value: ip, Expires: expiresAt(d)}
+	return bl.save()
+}
+
+// BanNickname bans a nickname for the given duration (0 = permanent).
+func (bl *BanList) BanNickname(nick string, d time.Duration) error {
+	bl.mu.Lock()
+	defer bl.mu.Unlock()
+	bl.nicknames[nick] = ban{Value: nick, Expires: expiresAt(d)}
+	return bl.save()
+}
+
+// BanFingerprint bans a public-key fingerprint for the given duration
+// (0 = permanent).
+func (bl *BanList) BanFingerprint(fp string, d time.Duration) error {
+	bl.mu.Lock()
+	defer bl.mu.Unlock()
+	bl.fingerprints[fp] = ban{Value: fp, Expires: expiresAt(d)}
+	return bl.save()
+}
+
+// Unban removes value from whichever set (IP or nickname) it appears
+// in, reporting whether anything was removed.
+func (bl *BanList) Unban(value string) bool {
+	bl.mu.Lock()
+	defer bl.mu.Unlock()
+
+	found := false
+	if _, ok := bl.ips[value]; ok {
+		delete(bl.ips, value)
+		found = true
+	}
+	if _, ok := bl.nicknames[value]; ok {
+		delete(bl.nicknames, value)
+		found = true
+	}
+	if found {
+		bl.save()
+	}
+	return found
+}
+
+// IsIPBanned reports whether ip is currently banned.
+func (bl *BanList) IsIPBanned(ip string) bool {
+	bl.mu.RLock()
+	defer bl.mu.RUnlock()
+	b, ok := bl.ips[ip]
+	return ok && !b.expired()
+}
+
+// IsNicknameBanned reports whether nick is currently banned.
+func (bl *BanList) IsNicknameBanned(nick string) bool {
+	bl.mu.RLock()
+	defer bl.mu.RUnlock()
+	b, ok := bl.nicknames[nick]
+	return ok && !b.expired()
+}
+
+// IsFingerprintBanned reports whether fp is currently banned.
+func (bl *BanList) IsFingerprintBanned(fp string) bool {
+	bl.mu.RLock()
+	defer bl.mu.RUnlock()
+	b, ok := bl.fingerprints[fp]
+	return ok && !b.expired()
+}
+
+// List returns a formatted line for every non-expired ban, for the
+// /banlist command.
+func (bl *BanList) List() []string {
+	bl.mu.RLock()
+	defer bl.mu.RUnlock()
+
+	var lines []string
+	for _, b := range bl.ips {
+		if !b.expired() {
+			lines = append(lines, formatBan("ip", b))
+		}
+	}
+	for _, b := range bl.nicknames {
+		if !b.expired() {
+			lines = append(lines, formatBan("nick", b))
+		}
+	}
+	for _, b := range bl.fingerprints {
+		if !b.expired() {
+			lines = append(lines, formatBan("fingerprint", b))
+		}
+	}
+	return lines
+}
+
+func formatBan(kind string, b ban) string {
+	if b.Expires.IsZero() {
+		return fmt.Sprintf("%s: %s (permanent)", kind, b.Value)
+	}
+	return fmt.Sprintf("%s: %s (expires %s)", kind, b.Value, b.Expires.Format(time.RFC3339))
+}
@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBanListIPLifecycle(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bans.json")
+	bl, err := NewBanList(path)
+	if err != nil {
+		t.Fatalf("NewBanList: %v", err)
+	}
+
+	if bl.IsIPBanned("1.2.3.4") {
+		t.Fatal("fresh ban list should not ban anything")
+	}
+
+	if err := bl.BanIP("1.2.3.4", 0); err != nil {
+		t.Fatalf("BanIP: %v", err)
+	}
+	if !bl.IsIPBanned("1.2.3.4") {
+		t.Fatal("expected 1.2.3.4 to be banned")
+	}
+
+	if !bl.Unban("1.2.3.4") {
+		t.Fatal("Unban should report the ban was found")
+	}
+	if bl.IsIPBanned("1.2.3.4") {
+		t.Fatal("1.2.3.4 should no longer be banned")
+	}
+	if bl.Unban("1.2.3.4") {
+		t.Fatal("Unban should report not found the second time")
+	}
+}
+
+func TestBanListExpiry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bans.json")
+	bl, err := NewBanList(path)
+	if err != nil {
+		t.Fatalf("NewBanList: %v", err)
+	}
+
+	if err := bl.BanNickname("eve", time.Millisecond); err != nil {
+		t.Fatalf("BanNickname: %v", err)
+	}
+	if !bl.IsNicknameBanned("eve") {
+		t.Fatal("eve should be banned immediately after BanNickname")
+	}
+
+	time.Sleep(5 * time.Millisecond)
+	if bl.IsNicknameBanned("eve") {
+		t.Fatal("eve's ban should have expired")
+	}
+	for _, line := range bl.List() {
+		if strings.HasPrefix(line, "nick: eve ") {
+			t.Fatalf("List() should not include expired ban, got %q", line)
+		}
+	}
+
+	// A subsequent write sweeps expired entries out of the in-memory
+	// maps entirely.
+	if err := bl.BanIP("9.9.9.9", 0); err != nil {
+		t.Fatalf("BanIP: %v", err)
+	}
+	if _, ok := bl.nicknames["eve"]; ok {
+		t.Fatal("expired nickname ban should have been purged on save")
+	}
+}
+
+func TestBanListPersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bans.json")
+
+	bl, err := NewBanList(path)
+	if err != nil {
+		t.Fatalf("NewBanList: %v", err)
+	}
+	if err := bl.BanFingerprint("ab:cd:ef", 0); err != nil {
+		t.Fatalf("BanFingerprint: %v", err)
+	}
+
+	reloaded, err := NewBanList(path)
+	if err != nil {
+		t.Fatalf("NewBanList (reload): %v", err)
+	}
+	if !reloaded.IsFingerprintBanned("ab:cd:ef") {
+		t.Fatal("expected ban to survive reload from disk")
+	}
+}
@@ -0,0 +1,22 @@
+package auth
+
+// Role identifies the privilege level associated with a nickname.
+type Role int
+
+const (
+	RoleUser Role = iota
+	RoleOp
+	RoleAdmin
+)
+
+// String returns the lowercase name used in /list and log output.
+func (r Role) String() string {
+	switch r {
+	case RoleAdmin:
+		return "admin"
+	case RoleOp:
+		return "op"
+	default:
+		return "user"
+	}
+}
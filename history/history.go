@@ -0,0 +1,71 @@
+// Package history implements a fixed-size ring buffer of broadcast
+// messages, letting a newly joined client catch up on recent chat.
+package history
+
+import (
+	"fmt"
+	"time"
+)
+
+// Entry is one broadcast message plus the metadata needed for
+// IRCv3-style server-time tags and /since catch-up.
+type Entry struct {
+	ID      uint64
+	Time    time.Time
+	Message string
+}
+
+// Tagged renders the entry's wire form: "@time=...;id=... <message>".
+// A plain-text client that doesn't understand tags can display
+// everything after the first space; richer clients parse the tags.
+func (e Entry) Tagged() string {
+	return fmt.Sprintf("@time=%s;id=%d %s", e.Time.UTC().Format("2006-01-02T15:04:05.000Z07:00"), e.ID, e.Message)
+}
+
+// History is a fixed-size ring buffer of recent broadcasts. It has no
+// internal locking: it is meant to be owned by a single goroutine (the
+// server's hub), the same way Server.clients is.
+type History struct {
+	size    int
+	entries []Entry
+	nextID  uint64
+}
+
+// New creates a History that retains at most size entries.
+func New(size int) *History {
+	return &History{size: size}
+}
+
+// Add records message as a new entry, stamping it with the next ID
+// and the given time, and returns the stored entry.
+func (h *History) Add(message string, at time.Time) Entry {
+	h.nextID++
+	e := Entry{ID: h.nextID, Time: at, Message: message}
+
+	h.entries = append(h.entries, e)
+	if len(h.entries) > h.size {
+		h.entries = h.entries[len(h.entries)-h.size:]
+	}
+
+	return e
+}
+
+// Recent returns the last n entries, or all stored entries if n <= 0
+// or n exceeds the number stored.
+func (h *History) Recent(n int) []Entry {
+	if n <= 0 || n > len(h.entries) {
+		n = len(h.entries)
+	}
+	return append([]Entry{}, h.entries[len(h.entries)-n:]...)
+}
+
+// Since returns every entry with an ID greater than id, oldest first.
+func (h *History) Since(id uint64) []Entry {
+	var out []Entry
+	for _, e := range h.entries {
+		if e.ID > id {
+			out = append(out, e)
+		}
+	}
+	return out
+}
@@ -0,0 +1,56 @@
+package history
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAddAssignsIncreasingIDs(t *testing.T) {
+	h := New(10)
+
+	e1 := h.Add("first", time.Now())
+	e2 := h.Add("second", time.Now())
+
+	if e1.ID != 1 || e2.ID != 2 {
+		t.Fatalf("got IDs %d, %d; want 1, 2", e1.ID, e2.ID)
+	}
+}
+
+func TestRecentHonorsSizeLimit(t *testing.T) {
+	h := New(3)
+	for i := 0; i < 5; i++ {
+		h.Add(string(rune('a'+i)), time.Now())
+	}
+
+	recent := h.Recent(10)
+	if len(recent) != 3 {
+		t.Fatalf("Recent(10) returned %d entries, want 3 (ring buffer capacity)", len(recent))
+	}
+	if recent[0].Message != "c" || recent[2].Message != "e" {
+		t.Fatalf("Recent(10) = %+v, want entries c, d, e in order", recent)
+	}
+}
+
+func TestRecentN(t *testing.T) {
+	h := New(10)
+	for i := 0; i < 5; i++ {
+		h.Add(string(rune('a'+i)), time.Now())
+	}
+
+	recent := h.Recent(2)
+	if len(recent) != 2 || recent[0].Message != "d" || recent[1].Message != "e" {
+		t.Fatalf("Recent(2) = %+v, want last two entries d, e", recent)
+	}
+}
+
+func TestSinceReturnsOnlyNewerEntries(t *testing.T) {
+	h := New(10)
+	h.Add("a", time.Now())
+	e2 := h.Add("b", time.Now())
+	e3 := h.Add("c", time.Now())
+
+	since := h.Since(e2.ID)
+	if len(since) != 1 || since[0].ID != e3.ID {
+		t.Fatalf("Since(%d) = %+v, want only entry %d", e2.ID, since, e3.ID)
+	}
+}
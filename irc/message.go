@@ -0,0 +1,98 @@
+// Package irc implements a small subset of the RFC 1459/2812 wire
+// protocol: enough line parsing and numeric-reply formatting for a
+// chat server to be driven by a standard IRC client.
+package irc
+
+import "strings"
+
+// Numeric replies used by this server. Names follow the RFC convention.
+const (
+	RplWelcome       = "001"
+	RplNamReply      = "353"
+	RplEndOfNames    = "366"
+	RplList          = "322"
+	RplListEnd       = "323"
+	RplWhoReply      = "352"
+	RplEndOfWho      = "315"
+	ErrNoSuchNick    = "401"
+	ErrNicknameInUse = "433"
+	ErrNotOnChannel  = "442"
+)
+
+// Message is a parsed client line: an optional ":prefix", a command,
+// and its parameters, with the final ":trailing" param (if any) kept
+// as a single element even when it contains spaces.
+type Message struct {
+	Prefix  string
+	Command string
+	Params  []string
+}
+
+// Parse splits a raw line (without the trailing CRLF) into a Message.
+func Parse(line string) Message {
+	var msg Message
+
+	line = strings.TrimRight(line, "\r\n")
+	if line == "" {
+		return msg
+	}
+
+	if strings.HasPrefix(line, ":") {
+		sp := strings.IndexByte(line, ' ')
+		if sp == -1 {
+			msg.Prefix = line[1:]
+			return msg
+		}
+		msg.Prefix = line[1:sp]
+		line = line[sp+1:]
+	}
+
+	var trailing string
+	hasTrailing := false
+	if idx := strings.Index(line, " :"); idx != -1 {
+		trailing = line[idx+2:]
+		hasTrailing = true
+		line = line[:idx]
+	} else if strings.HasPrefix(line, ":") {
+		trailing = line[1:]
+		hasTrailing = true
+		line = ""
+	}
+
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return msg
+	}
+
+	msg.Command = strings.ToUpper(fields[0])
+	msg.Params = append([]string{}, fields[1:]...)
+	if hasTrailing {
+		msg.Params = append(msg.Params, trailing)
+	}
+	return msg
+}
+
+// Reply formats a "<server> <code> <nick> <params...> :<trailing>" numeric
+// reply line, e.g. Reply("chat", RplWelcome, "alice", nil, "Welcome!").
+func Reply(server, code, nick string, params []string, trailing string) string {
+	var b strings.Builder
+	writeReply(&b, server, code, nick, params, trailing)
+	return b.String()
+}
+
+func writeReply(b *strings.Builder, server, code, nick string, params []string, trailing string) {
+	b.WriteByte(':')
+	b.WriteString(server)
+	b.WriteByte(' ')
+	b.WriteString(code)
+	b.WriteByte(' ')
+	b.WriteString(nick)
+	for _, p := range params {
+		b.WriteByte(' ')
+		b.WriteString(p)
+	}
+	if trailing != "" {
+		b.WriteString(" :")
+		b.WriteString(trailing)
+	}
+}
@@ -0,0 +1,66 @@
+package irc
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want Message
+	}{
+		{
+			name: "simple command",
+			line: "QUIT",
+			want: Message{Command: "QUIT", Params: []string{}},
+		},
+		{
+			name: "command with params",
+			line: "JOIN #lobby",
+			want: Message{Command: "JOIN", Params: []string{"#lobby"}},
+		},
+		{
+			name: "trailing with spaces",
+			line: "PRIVMSG alice :hello there, world",
+			want: Message{Command: "PRIVMSG", Params: []string{"alice", "hello there, world"}},
+		},
+		{
+			name: "prefix and command",
+			line: ":alice!user@host PRIVMSG #lobby :hi",
+			want: Message{Prefix: "alice!user@host", Command: "PRIVMSG", Params: []string{"#lobby", "hi"}},
+		},
+		{
+			name: "lowercase command is upcased",
+			line: "ping :go-socket-server",
+			want: Message{Command: "PING", Params: []string{"go-socket-server"}},
+		},
+		{
+			name: "trailing CRLF is trimmed",
+			line: "NICK alice\r\n",
+			want: Message{Command: "NICK", Params: []string{"alice"}},
+		},
+		{
+			name: "empty line",
+			line: "",
+			want: Message{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Parse(tt.line); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.line, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReply(t *testing.T) {
+	got := Reply("go-socket-server", RplWelcome, "alice", nil, "Welcome!")
+	want := ":go-socket-server 001 alice :Welcome!"
+	if got != want {
+		t.Errorf("Reply() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,42 @@
+// Package room models named groups of nicknames, the channel
+// abstraction IRC clients join with JOIN/PART.
+package room
+
+import "sort"
+
+// Room has no internal locking: it is meant to be owned by a single
+// goroutine (the server's hub) the same way Server.clients is.
+type Room struct {
+	Name    string
+	Members map[string]bool
+}
+
+// New creates an empty room.
+func New(name string) *Room {
+	return &Room{Name: name, Members: make(map[string]bool)}
+}
+
+// Join adds nick to the room.
+func (r *Room) Join(nick string) {
+	r.Members[nick] = true
+}
+
+// Leave removes nick from the room.
+func (r *Room) Leave(nick string) {
+	delete(r.Members, nick)
+}
+
+// Has reports whether nick is currently a member.
+func (r *Room) Has(nick string) bool {
+	return r.Members[nick]
+}
+
+// Names returns the room's members in sorted order, for NAMES/WHO replies.
+func (r *Room) Names() []string {
+	names := make([]string, 0, len(r.Members))
+	for nick := range r.Members {
+		names = append(names, nick)
+	}
+	sort.Strings(names)
+	return names
+}
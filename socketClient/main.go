@@ -8,6 +8,18 @@ import (
 	"strings"
 )
 
+// displayText strips a leading "@time=...;id=..." server-time tag
+// block from line, since this client has no use for it beyond display.
+func displayText(line string) string {
+	if !strings.HasPrefix(line, "@") {
+		return line
+	}
+	if sp := strings.IndexByte(line, ' '); sp != -1 {
+		return line[sp+1:]
+	}
+	return line
+}
+
 func main() {
 	// Connect to the server
 	conn, err := net.Dial("tcp", "localhost:8080")
@@ -25,7 +37,7 @@ func main() {
 	go func() {
 		scanner := bufio.NewScanner(conn)
 		for scanner.Scan() {
-			fmt.Println(scanner.Text())
+			fmt.Println(displayText(scanner.Text()))
 		}
 	}()
 
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eneidler/socketServerFull/auth"
+)
+
+// discardConn is a minimal net.Conn that discards writes, letting a
+// test drive the hub without a real socket.
+type discardConn struct {
+	net.Conn
+}
+
+func (discardConn) Write(p []byte) (int, error) { return len(p), nil }
+func (discardConn) Close() error                { return nil }
+func (discardConn) RemoteAddr() net.Addr        { return discardAddr{} }
+
+type discardAddr struct{}
+
+func (discardAddr) Network() string { return "tcp" }
+func (discardAddr) String() string  { return "127.0.0.1:0" }
+
+// TestEnqueueSurvivesConcurrentKick exercises the race between the hub
+// closing a kicked client's send channel and another goroutine calling
+// enqueue on that same *Client concurrently, the way ircKeepalive does
+// independently of the hub. Before Client.sendMu guarded both send and
+// close, this could panic the whole process with "send on closed
+// channel" instead of returning false.
+func TestEnqueueSurvivesConcurrentKick(t *testing.T) {
+	a, err := auth.New("", "", false)
+	if err != nil {
+		t.Fatalf("auth.New: %v", err)
+	}
+	s := NewServer(":0", "", a)
+	go s.run()
+
+	client := &Client{
+		conn:    discardConn{},
+		server:  s,
+		send:    make(chan string, sendBufferSize),
+		ignored: make(map[string]struct{}),
+	}
+	client.nickname = "eve"
+	if !s.addClient(client) {
+		t.Fatal("addClient: nickname should have been free")
+	}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				client.enqueue("PING")
+			}
+		}
+	}()
+
+	res := s.kickClient("eve")
+	if !res.found {
+		t.Fatal("kickClient: expected eve to be found")
+	}
+
+	// Give the enqueue goroutine time to keep hammering the now-closed
+	// channel; a panic here would crash the test binary.
+	time.Sleep(5 * time.Millisecond)
+	close(done)
+	wg.Wait()
+}
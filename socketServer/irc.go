@@ -0,0 +1,335 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/eneidler/socketServerFull/irc"
+)
+
+// serverName is the prefix this server uses in IRC numeric replies and
+// message framing (the "<server>" in ":<server> 001 nick ...").
+const serverName = "go-socket-server"
+
+// defaultChannel is where IRC clients land if they JOIN without
+// specifying a channel suffix, mirroring the plain-text protocol's
+// single lobby.
+const defaultChannel = "#lobby"
+
+// ircPingInterval and ircPongTimeout bound how long an idle IRC
+// connection is kept open: every ircPingInterval the server PINGs the
+// client, and if no PONG arrives within ircPongTimeout the connection
+// is closed. The plain-text protocol has no equivalent keepalive.
+const (
+	ircPingInterval = 60 * time.Second
+	ircPongTimeout  = 15 * time.Second
+)
+
+// handleIRCClient speaks the subset of RFC 1459/2812 needed for a
+// standard client (HexChat, WeeChat, ...) to register, join channels,
+// and chat.
+func (s *Server) handleIRCClient(conn net.Conn) {
+	defer conn.Close()
+
+	ip := remoteIP(conn)
+	if s.auth.Bans.IsIPBanned(ip) {
+		fmt.Fprintf(conn, "ERROR :Banned from this server\r\n")
+		return
+	}
+
+	client := &Client{
+		conn:     conn,
+		server:   s,
+		send:     make(chan string, sendBufferSize),
+		ircMode:  true,
+		channels: make(map[string]bool),
+	}
+	go client.writePump()
+
+	done := make(chan struct{})
+	defer close(done)
+	go client.ircKeepalive(done)
+
+	var pendingNick, pendingUser, pendingReal string
+	registered := false
+
+	scanner := bufio.NewScanner(conn)
+readLoop:
+	for scanner.Scan() {
+		msg := irc.Parse(scanner.Text())
+		if msg.Command == "" {
+			continue
+		}
+
+		switch msg.Command {
+		case "NICK":
+			if len(msg.Params) < 1 {
+				client.enqueue(irc.Reply(serverName, "431", "*", nil, "No nickname given"))
+				continue
+			}
+			nick := msg.Params[0]
+			if s.auth.Bans.IsNicknameBanned(nick) {
+				client.enqueue(irc.Reply(serverName, "432", "*", []string{nick}, "Nickname is banned"))
+				continue
+			}
+			pendingNick = nick
+			if !registered && pendingUser != "" {
+				registered = s.ircRegister(client, pendingNick, pendingUser, pendingReal)
+			}
+
+		case "USER":
+			if len(msg.Params) >= 4 {
+				pendingUser = msg.Params[0]
+				pendingReal = msg.Params[3]
+			}
+			if !registered && pendingNick != "" {
+				registered = s.ircRegister(client, pendingNick, pendingUser, pendingReal)
+			}
+
+		case "JOIN":
+			if registered {
+				s.ircJoin(client, msg.Params)
+			}
+
+		case "PART":
+			if registered {
+				s.ircPart(client, msg.Params)
+			}
+
+		case "PRIVMSG":
+			if registered {
+				s.ircPrivmsg(client, msg.Params)
+			}
+
+		case "NAMES":
+			s.ircNames(client, msg.Params)
+
+		case "LIST":
+			s.ircList(client)
+
+		case "WHO":
+			s.ircWho(client, msg.Params)
+
+		case "PING":
+			client.enqueue(irc.Reply(serverName, "PONG", serverName, nil, strings.Join(msg.Params, " ")))
+
+		case "PONG":
+			client.pongOK.Store(true)
+
+		case "QUIT":
+			break readLoop
+		}
+	}
+
+	for channel := range client.channels {
+		s.partRoom(client.nickname, channel)
+		s.sendToRoom(channel, client, fmt.Sprintf(":%s!%s@%s PART %s", client.nickname, client.username, ip, channel))
+	}
+
+	if registered {
+		s.removeClient(client)
+		fmt.Printf("IRC client %s disconnected\n", client.nickname)
+	} else {
+		client.closeSend()
+	}
+}
+
+// ircRegister completes NICK/USER registration once both have been
+// received, claiming the nickname on the shared hub.
+func (s *Server) ircRegister(client *Client, nick, user, real string) bool {
+	client.nickname = nick
+	client.username = user
+	client.realname = real
+
+	if !s.addClient(client) {
+		client.enqueue(irc.Reply(serverName, irc.ErrNicknameInUse, "*", []string{nick}, "Nickname is already in use"))
+		client.nickname = ""
+		return false
+	}
+
+	if s.auth.ClaimFirstAdmin(nick) {
+		client.enqueue(irc.Reply(serverName, "NOTICE", nick, nil, "You have been granted admin as the first client to connect"))
+	}
+
+	client.enqueue(irc.Reply(serverName, irc.RplWelcome, nick, nil, fmt.Sprintf("Welcome to the Go Socket Server, %s", nick)))
+	fmt.Printf("IRC client %s (%s) connected from %s\n", nick, s.auth.Role(nick), client.conn.RemoteAddr())
+	return true
+}
+
+// ircJoin handles "JOIN #chan[,#chan2,...]".
+func (s *Server) ircJoin(client *Client, params []string) {
+	if len(params) < 1 {
+		params = []string{defaultChannel}
+	}
+
+	for _, channel := range strings.Split(params[0], ",") {
+		channel = normalizeChannel(channel)
+
+		names := s.joinRoom(client.nickname, channel)
+		client.channels[channel] = true
+
+		announce := fmt.Sprintf(":%s!%s@%s JOIN %s", client.nickname, client.username, remoteIP(client.conn), channel)
+		client.enqueue(announce)
+		client.enqueue(irc.Reply(serverName, irc.RplNamReply, client.nickname, []string{"=", channel}, strings.Join(names, " ")))
+		client.enqueue(irc.Reply(serverName, irc.RplEndOfNames, client.nickname, []string{channel}, "End of /NAMES list"))
+		s.sendToRoom(channel, client, announce)
+	}
+}
+
+// ircPart handles "PART #chan[,#chan2,...]".
+func (s *Server) ircPart(client *Client, params []string) {
+	if len(params) < 1 {
+		client.enqueue(irc.Reply(serverName, "461", client.nickname, []string{"PART"}, "Not enough parameters"))
+		return
+	}
+
+	for _, channel := range strings.Split(params[0], ",") {
+		channel = normalizeChannel(channel)
+		if !client.channels[channel] {
+			client.enqueue(irc.Reply(serverName, irc.ErrNotOnChannel, client.nickname, []string{channel}, "You're not on that channel"))
+			continue
+		}
+
+		announce := fmt.Sprintf(":%s!%s@%s PART %s", client.nickname, client.username, remoteIP(client.conn), channel)
+		delete(client.channels, channel)
+		s.partRoom(client.nickname, channel)
+		client.enqueue(announce)
+		s.sendToRoom(channel, client, announce)
+	}
+}
+
+// ircPrivmsg handles "PRIVMSG <target> :<text>" for both channel and
+// direct-to-nickname targets.
+func (s *Server) ircPrivmsg(client *Client, params []string) {
+	if len(params) < 2 {
+		client.enqueue(irc.Reply(serverName, "461", client.nickname, []string{"PRIVMSG"}, "Not enough parameters"))
+		return
+	}
+
+	target, text := params[0], params[1]
+	line := fmt.Sprintf(":%s!%s@%s PRIVMSG %s :%s", client.nickname, client.username, remoteIP(client.conn), target, text)
+
+	if strings.HasPrefix(target, "#") {
+		s.sendToRoom(target, client, line)
+		return
+	}
+
+	if !s.deliverDirect(client.nickname, target, line) {
+		client.enqueue(irc.Reply(serverName, irc.ErrNoSuchNick, client.nickname, []string{target}, "No such nick"))
+	}
+}
+
+// ircNames handles "NAMES #chan".
+func (s *Server) ircNames(client *Client, params []string) {
+	if len(params) < 1 {
+		return
+	}
+	channel := normalizeChannel(params[0])
+	names := s.roomNames(channel)
+	client.enqueue(irc.Reply(serverName, irc.RplNamReply, client.nickname, []string{"=", channel}, strings.Join(names, " ")))
+	client.enqueue(irc.Reply(serverName, irc.RplEndOfNames, client.nickname, []string{channel}, "End of /NAMES list"))
+}
+
+// ircList handles "LIST".
+func (s *Server) ircList(client *Client) {
+	for _, info := range s.listRooms() {
+		client.enqueue(irc.Reply(serverName, irc.RplList, client.nickname, []string{info.name, fmt.Sprintf("%d", info.count)}, ""))
+	}
+	client.enqueue(irc.Reply(serverName, irc.RplListEnd, client.nickname, nil, "End of /LIST"))
+}
+
+// ircWho handles "WHO #chan".
+func (s *Server) ircWho(client *Client, params []string) {
+	if len(params) < 1 {
+		return
+	}
+	channel := normalizeChannel(params[0])
+	for _, nick := range s.roomNames(channel) {
+		client.enqueue(irc.Reply(serverName, irc.RplWhoReply, client.nickname, []string{channel, "*", "*", serverName, nick, "H"}, nick))
+	}
+	client.enqueue(irc.Reply(serverName, irc.RplEndOfWho, client.nickname, []string{channel}, "End of /WHO list"))
+}
+
+// normalizeChannel ensures a channel name carries its "#" prefix.
+func normalizeChannel(channel string) string {
+	channel = strings.TrimSpace(channel)
+	if channel == "" || strings.HasPrefix(channel, "#") {
+		return channel
+	}
+	return "#" + channel
+}
+
+// joinRoom asks the hub to add nick to channel and returns its members.
+func (s *Server) joinRoom(nick, channel string) []string {
+	resp := make(chan []string)
+	s.roomJoinCh <- roomJoinReq{nick: nick, channel: channel, result: resp}
+	return <-resp
+}
+
+// partRoom asks the hub to remove nick from channel.
+func (s *Server) partRoom(nick, channel string) {
+	s.roomPartCh <- roomPartReq{nick: nick, channel: channel}
+}
+
+// roomNames asks the hub for channel's current members.
+func (s *Server) roomNames(channel string) []string {
+	resp := make(chan []string)
+	s.roomNamesCh <- roomNamesReq{channel: channel, result: resp}
+	return <-resp
+}
+
+// listRooms asks the hub for a summary of every known channel.
+func (s *Server) listRooms() []roomInfo {
+	resp := make(chan []roomInfo)
+	s.roomListCh <- resp
+	return <-resp
+}
+
+// sendToRoom asks the hub to deliver line to every member of channel
+// except sender.
+func (s *Server) sendToRoom(channel string, sender *Client, line string) {
+	s.roomMsgCh <- roomMsgReq{channel: channel, sender: sender, line: line}
+}
+
+// deliverDirect asks the hub to deliver line straight to nick on
+// behalf of "from", reporting whether that nickname is currently
+// connected and not ignoring "from".
+func (s *Server) deliverDirect(from, nick, line string) bool {
+	resp := make(chan bool)
+	s.whisperCh <- whisperReq{from: from, nick: nick, line: line, result: resp}
+	return <-resp
+}
+
+// ircKeepalive pings the IRC client every ircPingInterval, whether or
+// not it has sent anything in between, and disconnects it if no PONG
+// arrives within ircPongTimeout, reaping half-open TCP connections.
+// Other traffic from the client does not count as a reply.
+func (c *Client) ircKeepalive(done <-chan struct{}) {
+	ticker := time.NewTicker(ircPingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			c.pongOK.Store(false)
+			if !c.enqueue(fmt.Sprintf("PING :%s", serverName)) {
+				return
+			}
+			select {
+			case <-done:
+				return
+			case <-time.After(ircPongTimeout):
+				if !c.pongOK.Load() {
+					fmt.Printf("IRC client %s timed out, disconnecting\n", c.nickname)
+					c.conn.Close()
+					return
+				}
+			}
+		}
+	}
+}
@@ -2,107 +2,617 @@ package main
 
 import (
 	"bufio"
+	"flag"
 	"fmt"
 	"net"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/eneidler/socketServerFull/auth"
+	"github.com/eneidler/socketServerFull/history"
+	"github.com/eneidler/socketServerFull/room"
 )
 
-// Client represents a connected client
+// sendBufferSize is how many outbound messages a client's writer
+// goroutine can queue before the hub considers it falling behind.
+const sendBufferSize = 16
+
+// historySize is how many recent broadcasts are retained for replay
+// to newly joined clients and the /history and /since commands.
+const historySize = 500
+
+// historyReplayCount is how many recent messages a newly joined
+// client is shown before their own "joined the chat" announcement.
+const historyReplayCount = 20
+
+// plainIdleTimeout bounds how long a plain-text connection may sit
+// without sending a line before it's considered half-open and closed.
+// The plain-text protocol has no PING/PONG framing to verify liveness
+// more actively (see ircPingInterval for the IRC listener's version),
+// so this is a generous ceiling rather than a tight keepalive.
+const plainIdleTimeout = 10 * time.Minute
+
+// Client represents a connected client. The irc* fields are only used
+// by handleIRCClient; plain-text clients leave them at their zero value.
+// away, awayReason, and ignored are mutated via setAway/setIgnore so
+// the hub's run() loop remains their only reader and writer.
+//
+// send is written to by whichever goroutine currently owns the
+// connection (its own read loop, ircKeepalive, ...) as well as being
+// closed by the hub on kick/ban/falling-behind/departure, so sendMu
+// guards both the send and the close: without it, a send racing a
+// close on an already-closed channel panics the process.
 type Client struct {
 	conn     net.Conn
 	nickname string
 	server   *Server
+
+	sendMu     sync.Mutex
+	send       chan string
+	sendClosed bool
+
+	away       bool
+	awayReason string
+	ignored    map[string]struct{}
+
+	ircMode  bool
+	username string
+	realname string
+	channels map[string]bool
+	pongOK   atomic.Bool
+}
+
+// enqueue queues message for delivery by the client's writer goroutine
+// without blocking. It reports whether the message was accepted; a
+// false return means the client's buffer is full or already closed.
+func (c *Client) enqueue(message string) bool {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+
+	if c.sendClosed {
+		return false
+	}
+	select {
+	case c.send <- message:
+		return true
+	default:
+		return false
+	}
+}
+
+// closeSend closes c.send, safe to call more than once or concurrently
+// with enqueue.
+func (c *Client) closeSend() {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+
+	if c.sendClosed {
+		return
+	}
+	c.sendClosed = true
+	close(c.send)
 }
 
-// Server manages all connected clients
+// writePump drains c.send and writes each message to the connection.
+// It exits once c.send is closed (via closeSend), whether on a clean
+// departure or a falling-behind disconnect. IRC clients are terminated
+// with CRLF, as RFC 1459/2812 requires; plain-text clients keep the
+// bare "\n" the socketClient reader already expects.
+func (c *Client) writePump() {
+	terminator := "\n"
+	if c.ircMode {
+		terminator = "\r\n"
+	}
+	for message := range c.send {
+		if _, err := c.conn.Write([]byte(message + terminator)); err != nil {
+			fmt.Printf("Error sending message to %s: %v\n", c.nickname, err)
+		}
+	}
+}
+
+// outboundMsg is a broadcast request processed by the hub.
+type outboundMsg struct {
+	message string
+	sender  *Client // nil means the message came from the server itself
+}
+
+// joinReq asks the hub to register a client under its chosen nickname.
+type joinReq struct {
+	client *Client
+	result chan bool // false if the nickname is already taken
+}
+
+// kickReq asks the hub to disconnect a client by nickname.
+type kickReq struct {
+	nickname string
+	result   chan kickResult
+}
+
+// kickResult reports whether the nickname was found and, if so, the IP
+// it was connected from (so callers like /ban can also ban the IP).
+type kickResult struct {
+	found bool
+	ip    string
+}
+
+// roomJoinReq asks the hub to add nick to channel, creating it if needed.
+type roomJoinReq struct {
+	nick    string
+	channel string
+	result  chan []string // members of the channel after joining
+}
+
+// roomPartReq asks the hub to remove nick from channel.
+type roomPartReq struct {
+	nick    string
+	channel string
+}
+
+// roomNamesReq asks the hub for the current members of channel.
+type roomNamesReq struct {
+	channel string
+	result  chan []string
+}
+
+// roomMsgReq asks the hub to deliver a pre-formatted line to every
+// member of channel except sender.
+type roomMsgReq struct {
+	channel string
+	sender  *Client
+	line    string
+}
+
+// roomInfo summarizes a channel for the /LIST reply.
+type roomInfo struct {
+	name  string
+	count int
+}
+
+// whisperReq asks the hub to deliver a pre-formatted line directly to
+// the client with the given nickname, from the client with the
+// nickname "from" (so the recipient's ignore set can be consulted).
+type whisperReq struct {
+	from   string
+	nick   string
+	line   string
+	result chan bool
+}
+
+// historyRecentReq asks the hub for the last n broadcasts.
+type historyRecentReq struct {
+	n      int
+	result chan []history.Entry
+}
+
+// historySinceReq asks the hub for every broadcast after id.
+type historySinceReq struct {
+	id     uint64
+	result chan []history.Entry
+}
+
+// setAwayReq asks the hub to update a client's away status.
+type setAwayReq struct {
+	client *Client
+	away   bool
+	reason string
+}
+
+// setIgnoreReq asks the hub to add or remove a nickname from a
+// client's ignore set.
+type setIgnoreReq struct {
+	client *Client
+	nick   string
+	ignore bool // false means unignore
+}
+
+// directMsgReq asks the hub to deliver a /msg to a nickname.
+type directMsgReq struct {
+	from   string
+	to     string
+	text   string
+	result chan directMsgResult
+}
+
+// directMsgResult reports the outcome of a directMsgReq.
+type directMsgResult struct {
+	found      bool // the target nickname is connected
+	ignored    bool // the target is ignoring the sender; message was dropped
+	away       bool
+	awayReason string
+}
+
+// Server manages all connected clients and IRC channels. Both the
+// client map and the room registry are owned exclusively by run(),
+// which processes events sent over channels; no other goroutine
+// touches them directly.
 type Server struct {
 	clients map[net.Conn]*Client
-	mutex   sync.RWMutex
+	rooms   map[string]*room.Room
+	history *history.History
 	address string
+	ircAddr string
+	auth    *auth.Auth
+
+	joinCh      chan joinReq
+	leaveCh     chan *Client
+	broadcastCh chan outboundMsg
+	kickCh      chan kickReq
+	listCh      chan chan string
+
+	roomJoinCh  chan roomJoinReq
+	roomPartCh  chan roomPartReq
+	roomNamesCh chan roomNamesReq
+	roomMsgCh   chan roomMsgReq
+	roomListCh  chan chan []roomInfo
+	whisperCh   chan whisperReq
+
+	historyRecentCh chan historyRecentReq
+	historySinceCh  chan historySinceReq
+
+	setAwayCh   chan setAwayReq
+	setIgnoreCh chan setIgnoreReq
+	directMsgCh chan directMsgReq
 }
 
-// NewServer creates a new server instance
-func NewServer(address string) *Server {
+// NewServer creates a new server instance. ircAddr may be empty to
+// disable the IRC-compatible listener.
+func NewServer(address, ircAddr string, a *auth.Auth) *Server {
 	return &Server{
-		clients: make(map[net.Conn]*Client),
-		address: address,
+		clients:     make(map[net.Conn]*Client),
+		rooms:       make(map[string]*room.Room),
+		history:     history.New(historySize),
+		address:     address,
+		ircAddr:     ircAddr,
+		auth:        a,
+		joinCh:      make(chan joinReq),
+		leaveCh:     make(chan *Client),
+		broadcastCh: make(chan outboundMsg),
+		kickCh:      make(chan kickReq),
+		listCh:      make(chan chan string),
+		roomJoinCh:  make(chan roomJoinReq),
+		roomPartCh:  make(chan roomPartReq),
+		roomNamesCh: make(chan roomNamesReq),
+		roomMsgCh:   make(chan roomMsgReq),
+		roomListCh:  make(chan chan []roomInfo),
+		whisperCh:   make(chan whisperReq),
+
+		historyRecentCh: make(chan historyRecentReq),
+		historySinceCh:  make(chan historySinceReq),
+
+		setAwayCh:   make(chan setAwayReq),
+		setIgnoreCh: make(chan setIgnoreReq),
+		directMsgCh: make(chan directMsgReq),
 	}
 }
 
-// Start begins listening for connections
+// Start begins listening for connections on the plain-text address
+// and, if configured, the IRC-compatible address.
 func (s *Server) Start() error {
-	// Listen on TCP port
 	listener, err := net.Listen("tcp", s.address)
 	if err != nil {
 		return fmt.Errorf("failed to start server: %w", err)
 	}
 	defer listener.Close()
 
+	go s.run()
+
+	if s.ircAddr != "" {
+		ircListener, err := net.Listen("tcp", s.ircAddr)
+		if err != nil {
+			return fmt.Errorf("failed to start irc listener: %w", err)
+		}
+		defer ircListener.Close()
+
+		fmt.Printf("IRC-compatible listener started on %s\n", s.ircAddr)
+		go s.acceptLoop(ircListener, s.handleIRCClient)
+	}
+
 	fmt.Printf("Socket server started on %s\n", s.address)
+	s.acceptLoop(listener, s.handleClient)
+	return nil
+}
 
-	// Accept connections in a loop
+// acceptLoop accepts connections from listener and hands each one to
+// handle in its own goroutine.
+func (s *Server) acceptLoop(listener net.Listener, handle func(net.Conn)) {
 	for {
 		conn, err := listener.Accept()
 		if err != nil {
 			fmt.Printf("Failed to accept connection: %v\n", err)
 			continue
 		}
+		go handle(conn)
+	}
+}
+
+// run owns s.clients and is the only goroutine allowed to read or
+// write it, so no locking is needed for membership or broadcast.
+func (s *Server) run() {
+	for {
+		select {
+		case req := <-s.joinCh:
+			taken := false
+			for _, c := range s.clients {
+				if c.nickname == req.client.nickname {
+					taken = true
+					break
+				}
+			}
+			if taken {
+				req.result <- false
+				continue
+			}
+			s.clients[req.client.conn] = req.client
+			req.result <- true
+
+		case c := <-s.leaveCh:
+			if _, ok := s.clients[c.conn]; ok {
+				delete(s.clients, c.conn)
+				c.closeSend()
+			}
+
+		case req := <-s.kickCh:
+			var res kickResult
+			for conn, c := range s.clients {
+				if c.nickname == req.nickname {
+					res = kickResult{found: true, ip: remoteIP(conn)}
+					c.enqueue("You have been kicked!")
+					delete(s.clients, conn)
+					c.closeSend()
+					go conn.Close()
+					break
+				}
+			}
+			req.result <- res
+
+		case resp := <-s.listCh:
+			resp <- s.formatClientList()
+
+		case msg := <-s.broadcastCh:
+			entry := s.history.Add(msg.message, time.Now())
+			line := entry.Tagged()
 
-		// Handle each client connection in a separate goroutine
-		go s.handleClient(conn)
+			for conn, c := range s.clients {
+				// IRC clients live in rooms (see roomMsgCh below), not
+				// the legacy whole-server broadcast.
+				if c.ircMode {
+					continue
+				}
+				// Don't send the message back to its sender
+				if msg.sender != nil && conn == msg.sender.conn {
+					continue
+				}
+				// Don't deliver to a recipient who is ignoring the sender
+				if msg.sender != nil {
+					if _, ignored := c.ignored[msg.sender.nickname]; ignored {
+						continue
+					}
+				}
+				if !c.enqueue(line) {
+					fmt.Printf("Client %s is falling behind, disconnecting\n", c.nickname)
+					delete(s.clients, conn)
+					c.closeSend()
+					go conn.Close()
+				}
+			}
+
+		case req := <-s.historyRecentCh:
+			req.result <- s.history.Recent(req.n)
+
+		case req := <-s.historySinceCh:
+			req.result <- s.history.Since(req.id)
+
+		case req := <-s.setAwayCh:
+			req.client.away = req.away
+			req.client.awayReason = req.reason
+
+		case req := <-s.setIgnoreCh:
+			if req.ignore {
+				req.client.ignored[req.nick] = struct{}{}
+			} else {
+				delete(req.client.ignored, req.nick)
+			}
+
+		case req := <-s.directMsgCh:
+			var res directMsgResult
+			for _, c := range s.clients {
+				if c.nickname != req.to {
+					continue
+				}
+				res.found = true
+				if _, ignored := c.ignored[req.from]; ignored {
+					res.ignored = true
+					break
+				}
+				res.away, res.awayReason = c.away, c.awayReason
+				c.enqueue(fmt.Sprintf("[PM from %s]: %s", req.from, req.text))
+				break
+			}
+			req.result <- res
+
+		case req := <-s.roomJoinCh:
+			r, ok := s.rooms[req.channel]
+			if !ok {
+				r = room.New(req.channel)
+				s.rooms[req.channel] = r
+			}
+			r.Join(req.nick)
+			req.result <- r.Names()
+
+		case req := <-s.roomPartCh:
+			if r, ok := s.rooms[req.channel]; ok {
+				r.Leave(req.nick)
+				if len(r.Members) == 0 {
+					delete(s.rooms, req.channel)
+				}
+			}
+
+		case req := <-s.roomNamesCh:
+			var names []string
+			if r, ok := s.rooms[req.channel]; ok {
+				names = r.Names()
+			}
+			req.result <- names
+
+		case resp := <-s.roomListCh:
+			infos := make([]roomInfo, 0, len(s.rooms))
+			for name, r := range s.rooms {
+				infos = append(infos, roomInfo{name: name, count: len(r.Members)})
+			}
+			resp <- infos
+
+		case req := <-s.roomMsgCh:
+			if r, ok := s.rooms[req.channel]; ok {
+				for conn, c := range s.clients {
+					if !r.Has(c.nickname) {
+						continue
+					}
+					if req.sender != nil && conn == req.sender.conn {
+						continue
+					}
+					c.enqueue(req.line)
+				}
+			}
+
+		case req := <-s.whisperCh:
+			found := false
+			for _, c := range s.clients {
+				if c.nickname == req.nick {
+					// Don't deliver to a recipient who is ignoring the
+					// sender; report as not found, same as directMsgCh.
+					if _, ignored := c.ignored[req.from]; ignored {
+						break
+					}
+					found = true
+					c.enqueue(req.line)
+					break
+				}
+			}
+			req.result <- found
+		}
 	}
 }
 
+// remoteIP extracts the bare IP (no port) from a connection's remote address.
+func remoteIP(conn net.Conn) string {
+	host, _, err := net.SplitHostPort(conn.RemoteAddr().String())
+	if err != nil {
+		return conn.RemoteAddr().String()
+	}
+	return host
+}
+
 // handleClient manages communication with a single client
 func (s *Server) handleClient(conn net.Conn) {
 	defer conn.Close()
 
+	ip := remoteIP(conn)
+	if s.auth.Bans.IsIPBanned(ip) {
+		fmt.Fprintf(conn, "You are banned from this server\n")
+		return
+	}
+
 	// Create new client
 	client := &Client{
-		conn:   conn,
-		server: s,
+		conn:    conn,
+		server:  s,
+		send:    make(chan string, sendBufferSize),
+		ignored: make(map[string]struct{}),
 	}
+	go client.writePump()
 
 	// Send welcome message
-	client.send("Welcome to the Go Socket Server!")
-	client.send("Please enter your nickname:")
+	client.enqueue("Welcome to the Go Socket Server!")
+	client.enqueue("Please enter your nickname:")
 
-	// Read nickname
 	scanner := bufio.NewScanner(conn)
-	if scanner.Scan() {
-		client.nickname = strings.TrimSpace(scanner.Text())
-		if client.nickname == "" {
-			client.nickname = "Anonymous"
+	for {
+		conn.SetReadDeadline(time.Now().Add(plainIdleTimeout))
+		if !scanner.Scan() {
+			client.closeSend()
+			return
+		}
+
+		nickname := strings.TrimSpace(scanner.Text())
+		if nickname == "" {
+			nickname = "Anonymous"
+		}
+
+		if s.auth.Bans.IsNicknameBanned(nickname) {
+			client.enqueue("That nickname is banned, choose another:")
+			continue
+		}
+
+		client.nickname = nickname
+		if s.addClient(client) {
+			break
 		}
+		client.enqueue("That nickname is already in use, choose another:")
 	}
 
-	// Add client to server's client list
-	s.addClient(client)
+	if s.auth.ClaimFirstAdmin(client.nickname) {
+		client.enqueue("You have been granted admin as the first client to connect")
+	}
+
+	// Replay recent history so the new client can catch up before
+	// their own arrival is announced.
+	for _, entry := range s.historyRecent(historyReplayCount) {
+		client.enqueue(entry.Tagged())
+	}
 
 	// Notify others of new client
 	s.broadcast(fmt.Sprintf("%s joined the chat", client.nickname), client)
 
-	fmt.Printf("Client %s connected from %s\n", client.nickname, conn.RemoteAddr())
+	fmt.Printf("Client %s (%s) connected from %s\n", client.nickname, s.auth.Role(client.nickname), conn.RemoteAddr())
 
 	// Listen for messages from this client
-	for scanner.Scan() {
+	for {
+		conn.SetReadDeadline(time.Now().Add(plainIdleTimeout))
+		if !scanner.Scan() {
+			break
+		}
 		message := strings.TrimSpace(scanner.Text())
 
 		if message == "" {
 			continue
 		}
 
-		// Handle special commands
+		// Handle special commands. More specific prefixes (e.g. /banlist)
+		// are checked before the shorter prefixes they'd otherwise match
+		// (e.g. /ban).
 		if strings.HasPrefix(message, "/quit") {
 			break
 		} else if strings.HasPrefix(message, "/list") {
-			client.send(s.getClientList())
+			client.enqueue(s.getClientList())
 		} else if strings.HasPrefix(message, "/time") {
-			client.send("Server time: " + time.Now().Format("15:04:05"))
+			client.enqueue("Server time: " + time.Now().Format("15:04:05"))
 		} else if strings.HasPrefix(message, "/kick") {
 			s.handleKickClient(message, client)
+		} else if strings.HasPrefix(message, "/banlist") {
+			s.handleBanList(client)
+		} else if strings.HasPrefix(message, "/ban") {
+			s.handleBanClient(message, client)
+		} else if strings.HasPrefix(message, "/unban") {
+			s.handleUnbanClient(message, client)
+		} else if strings.HasPrefix(message, "/op") {
+			s.handleOpClient(message, client)
+		} else if strings.HasPrefix(message, "/history") {
+			s.handleHistory(message, client)
+		} else if strings.HasPrefix(message, "/since") {
+			s.handleSince(message, client)
+		} else if strings.HasPrefix(message, "/msg") {
+			s.handleMsgClient(message, client)
+		} else if strings.HasPrefix(message, "/away") {
+			s.handleAwayClient(message, client)
+		} else if strings.HasPrefix(message, "/back") {
+			s.handleBackClient(client)
+		} else if strings.HasPrefix(message, "/unignore") {
+			s.handleIgnoreClient(message, client, false)
+		} else if strings.HasPrefix(message, "/ignore") {
+			s.handleIgnoreClient(message, client, true)
 		} else {
 			// Broadcast regular message to all clients
 			fullMessage := fmt.Sprintf("[%s]: %s", client.nickname, message)
@@ -116,88 +626,289 @@ func (s *Server) handleClient(conn net.Conn) {
 	fmt.Printf("Client %s disconnected\n", client.nickname)
 }
 
-// send sends a message to this specific client
-func (c *Client) send(message string) {
-	_, err := c.conn.Write([]byte(message + "\n"))
-	if err != nil {
-		fmt.Printf("Error sending message to %s: %v\n", c.nickname, err)
-	}
+// addClient registers client with the hub under its current nickname,
+// reporting whether the nickname was free.
+func (s *Server) addClient(client *Client) bool {
+	resp := make(chan bool)
+	s.joinCh <- joinReq{client: client, result: resp}
+	return <-resp
 }
 
-// addClient adds a client to the server's client list (thread-safe)
-func (s *Server) addClient(client *Client) {
-	s.mutex.Lock()
-	defer s.mutex.Unlock()
-	s.clients[client.conn] = client
+// removeClient removes a client from the server's client list.
+func (s *Server) removeClient(client *Client) {
+	s.leaveCh <- client
 }
 
-// removeClient removes a client from the server's client list (thread-safe)
-func (s *Server) removeClient(client *Client) {
-	s.mutex.Lock()
-	defer s.mutex.Unlock()
-	delete(s.clients, client.conn)
+// kickClient asks the hub to forcibly disconnect the named client.
+func (s *Server) kickClient(nickname string) kickResult {
+	resp := make(chan kickResult)
+	s.kickCh <- kickReq{nickname: nickname, result: resp}
+	return <-resp
+}
+
+// handleKickClient handles the request to kick a client from the server
+func (s *Server) handleKickClient(message string, sender *Client) {
+	if !s.auth.IsOp(sender.nickname) {
+		sender.enqueue("permission denied")
+		return
+	}
+
+	messageSplit := strings.SplitN(message, " ", 2)
+	if len(messageSplit) != 2 {
+		sender.enqueue("Usage: /kick <nickname>")
+		return
+	}
+
+	targetNickname := messageSplit[1]
+
+	res := s.kickClient(targetNickname)
+	if res.found {
+		sender.enqueue(fmt.Sprintf("You have kicked %s", targetNickname))
+		s.broadcast(fmt.Sprintf("%s was kicked by %s", targetNickname, sender.nickname), nil)
+	}
 }
 
-// kickClient forcibly kicks client from the server and notifies them (thread-safe)
-func (s *Server) kickClient(nickname string) bool {
-	s.mutex.Lock()
-	defer s.mutex.Unlock()
+// handleBanClient handles "/ban <nick> [duration]": bans the nickname
+// (and, if currently connected, their IP too) and kicks them.
+func (s *Server) handleBanClient(message string, sender *Client) {
+	if !s.auth.IsAdmin(sender.nickname) {
+		sender.enqueue("permission denied")
+		return
+	}
 
-	for conn, client := range s.clients {
-		if client.nickname == nickname {
-			client.send("You have been kicked!")
+	fields := strings.Fields(message)
+	if len(fields) < 2 {
+		sender.enqueue("Usage: /ban <nickname> [duration]")
+		return
+	}
 
-			delete(s.clients, conn)
+	targetNickname := fields[1]
+	var d time.Duration
+	if len(fields) >= 3 {
+		parsed, err := time.ParseDuration(fields[2])
+		if err != nil {
+			sender.enqueue(fmt.Sprintf("Invalid duration %q", fields[2]))
+			return
+		}
+		d = parsed
+	}
 
-			go func() {
-				err := conn.Close()
-				if err != nil {
-					return
-				}
-			}()
+	if err := s.auth.Bans.BanNickname(targetNickname, d); err != nil {
+		sender.enqueue(fmt.Sprintf("Failed to save ban: %v", err))
+		return
+	}
 
-			return true
+	if res := s.kickClient(targetNickname); res.found {
+		if err := s.auth.Bans.BanIP(res.ip, d); err != nil {
+			sender.enqueue(fmt.Sprintf("Failed to save ban: %v", err))
 		}
 	}
-	return false
+
+	sender.enqueue(fmt.Sprintf("Banned %s", targetNickname))
 }
 
-// handleKickClient handles the request to kick a client from the server
-func (s *Server) handleKickClient(message string, sender *Client) {
+// handleUnbanClient handles "/unban <nickname|ip>".
+func (s *Server) handleUnbanClient(message string, sender *Client) {
+	if !s.auth.IsAdmin(sender.nickname) {
+		sender.enqueue("permission denied")
+		return
+	}
+
 	messageSplit := strings.SplitN(message, " ", 2)
 	if len(messageSplit) != 2 {
-		sender.send("Usage: /kick <nickname>")
+		sender.enqueue("Usage: /unban <nickname|ip>")
 		return
 	}
 
-	targetNickname := messageSplit[1]
+	if s.auth.Bans.Unban(strings.TrimSpace(messageSplit[1])) {
+		sender.enqueue(fmt.Sprintf("Unbanned %s", messageSplit[1]))
+	} else {
+		sender.enqueue(fmt.Sprintf("%s is not banned", messageSplit[1]))
+	}
+}
 
-	kicked := s.kickClient(targetNickname)
-	if kicked {
-		sender.send(fmt.Sprintf("You have kicked %s", targetNickname))
-		s.broadcast(fmt.Sprintf("%s was kicked by %s", targetNickname, sender.nickname), nil)
+// handleOpClient handles "/op <nickname>".
+func (s *Server) handleOpClient(message string, sender *Client) {
+	if !s.auth.IsAdmin(sender.nickname) {
+		sender.enqueue("permission denied")
+		return
 	}
+
+	messageSplit := strings.SplitN(message, " ", 2)
+	if len(messageSplit) != 2 {
+		sender.enqueue("Usage: /op <nickname>")
+		return
+	}
+
+	targetNickname := strings.TrimSpace(messageSplit[1])
+	s.auth.Op(targetNickname)
+	sender.enqueue(fmt.Sprintf("%s is now an op", targetNickname))
 }
 
-// broadcast sends a message to all connected clients except the sender
-func (s *Server) broadcast(message string, sender *Client) {
-	s.mutex.RLock()
-	defer s.mutex.RUnlock()
+// handleBanList handles "/banlist".
+func (s *Server) handleBanList(sender *Client) {
+	if !s.auth.IsAdmin(sender.nickname) {
+		sender.enqueue("permission denied")
+		return
+	}
 
-	for conn, client := range s.clients {
-		// Don't send message back to sender
-		if sender != nil && conn == sender.conn {
-			continue
+	bans := s.auth.Bans.List()
+	if len(bans) == 0 {
+		sender.enqueue("No active bans")
+		return
+	}
+
+	result := fmt.Sprintf("Active bans (%d):", len(bans))
+	for _, b := range bans {
+		result += fmt.Sprintf("\n- %s", b)
+	}
+	sender.enqueue(result)
+}
+
+// handleHistory handles "/history [N]", replaying the last N (default
+// historyReplayCount) broadcasts to the requester.
+func (s *Server) handleHistory(message string, sender *Client) {
+	n := historyReplayCount
+	fields := strings.Fields(message)
+	if len(fields) >= 2 {
+		parsed, err := strconv.Atoi(fields[1])
+		if err != nil || parsed <= 0 {
+			sender.enqueue("Usage: /history [N]")
+			return
+		}
+		n = parsed
+	}
+
+	for _, entry := range s.historyRecent(n) {
+		sender.enqueue(entry.Tagged())
+	}
+}
+
+// handleSince handles "/since <id>", replaying every broadcast after id.
+func (s *Server) handleSince(message string, sender *Client) {
+	fields := strings.Fields(message)
+	if len(fields) != 2 {
+		sender.enqueue("Usage: /since <id>")
+		return
+	}
+
+	id, err := strconv.ParseUint(fields[1], 10, 64)
+	if err != nil {
+		sender.enqueue("Usage: /since <id>")
+		return
+	}
+
+	for _, entry := range s.historySince(id) {
+		sender.enqueue(entry.Tagged())
+	}
+}
+
+// handleMsgClient handles "/msg <nickname> <text>", a private message
+// routed only to the named recipient.
+func (s *Server) handleMsgClient(message string, sender *Client) {
+	parts := strings.SplitN(message, " ", 3)
+	if len(parts) != 3 {
+		sender.enqueue("Usage: /msg <nickname> <text>")
+		return
+	}
+
+	target, text := parts[1], parts[2]
+	res := s.sendDirect(sender.nickname, target, text)
+	if !res.found || res.ignored {
+		sender.enqueue(fmt.Sprintf("%s not found", target))
+		return
+	}
+
+	sender.enqueue(fmt.Sprintf("[PM to %s]: %s", target, text))
+	if res.away {
+		sender.enqueue(fmt.Sprintf("%s is away: %s", target, res.awayReason))
+	}
+}
+
+// handleAwayClient handles "/away [reason]".
+func (s *Server) handleAwayClient(message string, client *Client) {
+	reason := "Away"
+	if parts := strings.SplitN(message, " ", 2); len(parts) == 2 && strings.TrimSpace(parts[1]) != "" {
+		reason = strings.TrimSpace(parts[1])
+	}
+
+	s.setAway(client, true, reason)
+	client.enqueue(fmt.Sprintf("You are now away: %s", reason))
+}
+
+// handleBackClient handles "/back".
+func (s *Server) handleBackClient(client *Client) {
+	s.setAway(client, false, "")
+	client.enqueue("Welcome back")
+}
+
+// handleIgnoreClient handles "/ignore <nickname>" and "/unignore <nickname>".
+func (s *Server) handleIgnoreClient(message string, client *Client, ignore bool) {
+	parts := strings.SplitN(message, " ", 2)
+	if len(parts) != 2 || strings.TrimSpace(parts[1]) == "" {
+		sender := "/ignore"
+		if !ignore {
+			sender = "/unignore"
 		}
-		client.send(message)
+		client.enqueue(fmt.Sprintf("Usage: %s <nickname>", sender))
+		return
+	}
+
+	nick := strings.TrimSpace(parts[1])
+	s.setIgnore(client, nick, ignore)
+	if ignore {
+		client.enqueue(fmt.Sprintf("Ignoring %s", nick))
+	} else {
+		client.enqueue(fmt.Sprintf("No longer ignoring %s", nick))
 	}
 }
 
+// broadcast sends a message to all connected clients except the sender
+func (s *Server) broadcast(message string, sender *Client) {
+	s.broadcastCh <- outboundMsg{message: message, sender: sender}
+}
+
 // getClientList returns a formatted list of connected clients
 func (s *Server) getClientList() string {
-	s.mutex.RLock()
-	defer s.mutex.RUnlock()
+	resp := make(chan string)
+	s.listCh <- resp
+	return <-resp
+}
+
+// historyRecent returns the last n broadcasts.
+func (s *Server) historyRecent(n int) []history.Entry {
+	resp := make(chan []history.Entry)
+	s.historyRecentCh <- historyRecentReq{n: n, result: resp}
+	return <-resp
+}
+
+// historySince returns every broadcast after id.
+func (s *Server) historySince(id uint64) []history.Entry {
+	resp := make(chan []history.Entry)
+	s.historySinceCh <- historySinceReq{id: id, result: resp}
+	return <-resp
+}
+
+// setAway updates client's away status on the hub.
+func (s *Server) setAway(client *Client, away bool, reason string) {
+	s.setAwayCh <- setAwayReq{client: client, away: away, reason: reason}
+}
+
+// setIgnore adds or removes nick from client's ignore set on the hub.
+func (s *Server) setIgnore(client *Client, nick string, ignore bool) {
+	s.setIgnoreCh <- setIgnoreReq{client: client, nick: nick, ignore: ignore}
+}
+
+// sendDirect asks the hub to deliver a /msg from "from" to "to".
+func (s *Server) sendDirect(from, to, text string) directMsgResult {
+	resp := make(chan directMsgResult)
+	s.directMsgCh <- directMsgReq{from: from, to: to, text: text, result: resp}
+	return <-resp
+}
 
+// formatClientList builds the /list response. Only run() may call this,
+// since it reads s.clients directly.
+func (s *Server) formatClientList() string {
 	if len(s.clients) == 0 {
 		return "No clients connected"
 	}
@@ -205,13 +916,32 @@ func (s *Server) getClientList() string {
 	result := fmt.Sprintf("Connected clients (%d):", len(s.clients))
 	for _, client := range s.clients {
 		result += fmt.Sprintf("\n- %s", client.nickname)
+		if role := s.auth.Role(client.nickname); role != auth.RoleUser {
+			result += fmt.Sprintf(" [%s]", role)
+		}
+		if client.away {
+			result += fmt.Sprintf(" (away: %s)", client.awayReason)
+		}
 	}
 	return result
 }
 
 func main() {
+	address := flag.String("address", ":8080", "address to listen on")
+	ircAddr := flag.String("irc", "", "address for an IRC-compatible listener (e.g. :6667); empty disables it")
+	bansPath := flag.String("bans", "bans.json", "path to the persisted ban list")
+	adminsPath := flag.String("admins", "", "path to a config file of admin nicknames/fingerprints")
+	firstAdmin := flag.Bool("first-admin", false, "grant admin to the first client that connects")
+	flag.Parse()
+
+	a, err := auth.New(*bansPath, *adminsPath, *firstAdmin)
+	if err != nil {
+		fmt.Printf("Failed to initialize auth: %v\n", err)
+		return
+	}
+
 	// Create and start the server
-	server := NewServer(":8080")
+	server := NewServer(*address, *ircAddr, a)
 
 	fmt.Println("Starting Go Socket Server...")
 	if err := server.Start(); err != nil {